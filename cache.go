@@ -3,19 +3,34 @@ package cache
 // modified version of https://raw.githubusercontent.com/patrickmn/go-cache
 // made the following changes...
 // 1. the ability to keep expired items in the cache and return expired items when asked for
-// 2. change expiration management from per-key to entire cache
+// 2. change expiration management from per-key to entire cache, with optional per-key overrides
 // 3. execute a callback function when the cache expires
 // 4. removed increment/decrement
-// 5. removed ability to persist cache to disk
+// 5. removed ability to persist cache to disk (reintroduced as stream-based Save/Load, see persist.go)
 // 6. removed auto eviction of expired items
+// 7. backing storage moved behind a Store interface (see store.go), so the
+//    default map+RWMutex store can be swapped for a sharded or LRU-bounded one
+// 8. janitor shutdown driven by context + Close() instead of a finalizer
 
 import (
+	"context"
 	"fmt"
-	"runtime"
 	"sync"
 	"time"
 )
 
+const (
+	// NoExpiration is a sentinel duration passed to SetWithExpiration (and
+	// friends) to indicate that an item should never expire, regardless of
+	// the cache-wide expiration.
+	NoExpiration time.Duration = -1
+
+	// DefaultExpiration is a sentinel duration passed to SetWithExpiration
+	// (and friends) to indicate that the cache-wide expiration should be
+	// used for that item.
+	DefaultExpiration time.Duration = 0
+)
+
 // Item represents an item in the cache.
 type Item struct {
 	Object     interface{}
@@ -38,86 +53,150 @@ type Cache struct {
 
 type cache struct {
 	expiration time.Duration
-	items      map[string]Item
-	mu         sync.RWMutex
-	onExpired  func()
+	store      Store
+	callbackMu sync.RWMutex // guards onExpired and onEvicted
+	onExpired  func(ctx context.Context)
+	onEvicted  func(key string, value interface{})
 	janitor    *janitor
+	keyFunc    func(interface{}) (string, error)
 }
 
 // Set add an item to the cache, replacing any existing item.
 func (c *cache) Set(k string, x interface{}) {
-	// "Inlining" of set
-	var e = time.Now().Add(c.expiration).UnixNano()
-
-	c.mu.Lock()
-	c.items[k] = Item{
+	c.store.Set(k, Item{
 		Object:     x,
-		Expiration: e,
-	}
-	// TODO: Calls to mu.Unlock are currently not deferred because defer
-	// adds ~200 ns (as of go1.)
-	c.mu.Unlock()
+		Expiration: c.expirationFor(DefaultExpiration),
+	})
 }
 
-func (c *cache) set(k string, x interface{}) {
-	var e = time.Now().Add(c.expiration).UnixNano()
-	c.items[k] = Item{
+// SetWithExpiration adds an item to the cache, replacing any existing item,
+// using d instead of the cache-wide expiration. Pass DefaultExpiration to
+// use the cache-wide expiration, or NoExpiration to make the item never
+// expire.
+func (c *cache) SetWithExpiration(k string, x interface{}, d time.Duration) {
+	c.store.Set(k, Item{
 		Object:     x,
-		Expiration: e,
+		Expiration: c.expirationFor(d),
+	})
+}
+
+// expirationFor resolves d (a duration, or the DefaultExpiration/NoExpiration
+// sentinels) against the cache-wide expiration into an absolute expiration
+// timestamp suitable for Item.Expiration.
+func (c *cache) expirationFor(d time.Duration) int64 {
+	if d == DefaultExpiration {
+		d = c.expiration
+	}
+	if d == NoExpiration {
+		return 0
 	}
+	return time.Now().Add(d).UnixNano()
 }
 
 // Replace set a new value for the cache key only if it already exists. Returns an error otherwise.
 func (c *cache) Replace(k string, x interface{}) error {
-	c.mu.Lock()
-	_, found := c.get(k)
-	if !found {
-		c.mu.Unlock()
-		return fmt.Errorf("Item %s doesn't exist", k)
-	}
-	c.set(k, x)
-	c.mu.Unlock()
-	return nil
+	return c.store.Replace(k, Item{
+		Object:     x,
+		Expiration: c.expirationFor(DefaultExpiration),
+	})
+}
+
+// ReplaceWithExpiration sets a new value, using d instead of the cache-wide
+// expiration, for the cache key only if it already exists. Returns an error
+// otherwise.
+func (c *cache) ReplaceWithExpiration(k string, x interface{}, d time.Duration) error {
+	return c.store.Replace(k, Item{
+		Object:     x,
+		Expiration: c.expirationFor(d),
+	})
+}
+
+// Add an item to the cache, using d instead of the cache-wide expiration,
+// only if it doesn't already exist. Returns an error if the key already
+// exists.
+func (c *cache) Add(k string, x interface{}, d time.Duration) error {
+	return c.store.Add(k, Item{
+		Object:     x,
+		Expiration: c.expirationFor(d),
+	})
 }
 
 // Get an item from the cache. Returns the item or nil, and a bool indicating
 // whether the key was found.
 func (c *cache) Get(k string) (interface{}, bool) {
-	c.mu.RLock()
-	item, found := c.items[k]
+	item, found := c.store.Get(k)
 	if !found {
-		c.mu.RUnlock()
 		return nil, false
 	}
-	c.mu.RUnlock()
 	return item.Object, true
 }
 
-func (c *cache) get(k string) (interface{}, bool) {
-	item, found := c.items[k]
-	if !found {
-		return nil, false
+// keyFor resolves obj to a cache key using the function configured via
+// WithKeyFunc. Returns an error if no key function was configured.
+func (c *cache) keyFor(obj interface{}) (string, error) {
+	if c.keyFunc == nil {
+		return "", fmt.Errorf("cache: no key function configured, see WithKeyFunc")
 	}
-	return item.Object, true
+	return c.keyFunc(obj)
+}
+
+// GetObj is like Get, but derives the cache key from obj via the function
+// configured with WithKeyFunc instead of taking a pre-formatted string key.
+func (c *cache) GetObj(obj interface{}) (interface{}, bool, error) {
+	k, err := c.keyFor(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	x, found := c.Get(k)
+	return x, found, nil
+}
+
+// SetObj is like Set, but derives the cache key from obj via the function
+// configured with WithKeyFunc instead of taking a pre-formatted string key.
+func (c *cache) SetObj(obj interface{}, x interface{}) error {
+	k, err := c.keyFor(obj)
+	if err != nil {
+		return err
+	}
+	c.Set(k, x)
+	return nil
+}
+
+// DeleteObj is like Delete, but derives the cache key from obj via the
+// function configured with WithKeyFunc instead of taking a pre-formatted
+// string key.
+func (c *cache) DeleteObj(obj interface{}) error {
+	k, err := c.keyFor(obj)
+	if err != nil {
+		return err
+	}
+	c.Delete(k)
+	return nil
 }
 
 // Delete an item from the cache. Does nothing if the key is not in the cache.
 func (c *cache) Delete(k string) {
-	c.mu.Lock()
-	delete(c.items, k)
-	c.mu.Unlock()
+	item, found := c.store.Delete(k)
+	if found {
+		if f := c.evictedFunc(); f != nil {
+			f(k, item.Object)
+		}
+	}
 }
 
 // Delete all expired items from the cache.
 func (c *cache) DeleteExpired() {
 	now := time.Now().UnixNano()
-	c.mu.Lock()
-	for k, v := range c.items {
-		if v.Expiration > 0 && now > v.Expiration {
-			delete(c.items, k)
+	var expired []string
+	c.store.Range(func(k string, item Item) bool {
+		if item.Expiration > 0 && now > item.Expiration {
+			expired = append(expired, k)
 		}
+		return true
+	})
+	for _, k := range expired {
+		c.Delete(k)
 	}
-	c.mu.Unlock()
 }
 
 type keyAndValue struct {
@@ -125,95 +204,190 @@ type keyAndValue struct {
 	value interface{}
 }
 
-// OnExpired sets an (optional) function that is called when the cache expires
-func (c *cache) OnExpired(f func()) {
+// OnExpired sets an (optional) function that is called, with the janitor's
+// context, on each cache-wide expiration tick.
+func (c *cache) OnExpired(f func(ctx context.Context)) {
+	c.callbackMu.Lock()
 	c.onExpired = f
+	c.callbackMu.Unlock()
+}
+
+// OnEvicted sets an (optional) function that is called whenever an item
+// leaves the cache via Delete, DeleteExpired, Flush, or — for a bounded LRU
+// store — automatic eviction of the least-recently-used item. This is
+// distinct from OnExpired, which fires on the cache-wide janitor tick
+// rather than per key.
+func (c *cache) OnEvicted(f func(key string, value interface{})) {
+	c.callbackMu.Lock()
+	c.onEvicted = f
+	c.callbackMu.Unlock()
+	if en, ok := c.store.(evictionNotifier); ok {
+		en.onEvict(f)
+	}
+}
+
+// evictedFunc returns the callback set by OnEvicted, if any, guarding
+// against concurrent OnEvicted calls.
+func (c *cache) evictedFunc() func(key string, value interface{}) {
+	c.callbackMu.RLock()
+	defer c.callbackMu.RUnlock()
+	return c.onEvicted
+}
+
+// expiredFunc returns the callback set by OnExpired, if any, guarding
+// against concurrent OnExpired calls.
+func (c *cache) expiredFunc() func(ctx context.Context) {
+	c.callbackMu.RLock()
+	defer c.callbackMu.RUnlock()
+	return c.onExpired
+}
+
+// GetWithExpiration returns an item and its expiration time from the cache.
+// It returns the zero time if the item has no expiration.
+func (c *cache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	item, found := c.store.Get(k)
+	if !found {
+		return nil, time.Time{}, false
+	}
+	if item.Expiration == 0 {
+		return item.Object, time.Time{}, true
+	}
+	return item.Object, time.Unix(0, item.Expiration), true
+}
+
+// Items returns a snapshot copy of all items in the cache, including
+// expired items.
+func (c *cache) Items() map[string]Item {
+	items := make(map[string]Item)
+	c.store.Range(func(k string, item Item) bool {
+		items[k] = item
+		return true
+	})
+	return items
 }
 
 // Returns the number of items in the cache, including expired items.
 func (c *cache) ItemCount() int {
-	c.mu.RLock()
-	n := len(c.items)
-	c.mu.RUnlock()
-	return n
+	return c.store.Len()
 }
 
 // Delete all items from the cache.
 func (c *cache) Flush() {
-	c.mu.Lock()
-	c.items = map[string]Item{}
-	c.mu.Unlock()
+	if f := c.evictedFunc(); f != nil {
+		items := c.Items()
+		c.store.Flush()
+		for k, item := range items {
+			f(k, item.Object)
+		}
+		return
+	}
+	c.store.Flush()
 }
 
 type janitor struct {
 	Interval time.Duration
-	stop     chan bool
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
 }
 
-// handleExpired is fired by the ticker and executes the onExpired function.
-func (c *cache) handleExpired() {
-	if c.onExpired != nil {
-		c.onExpired()
+// handleExpired is fired by the ticker and executes the onExpired function
+// with the janitor's context.
+func (c *cache) handleExpired(ctx context.Context) {
+	if f := c.expiredFunc(); f != nil {
+		f(ctx)
 	}
 }
 
-func (j *janitor) Run(c *cache) {
+// Run ticks every j.Interval until ctx is cancelled.
+func (j *janitor) Run(ctx context.Context, c *cache) {
+	defer j.wg.Done()
 	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			c.handleExpired()
-		case <-j.stop:
-			ticker.Stop()
+			c.handleExpired(ctx)
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func stopJanitor(c *Cache) {
-	c.janitor.stop <- true
-}
-
-func runJanitor(c *cache, ex time.Duration) {
+func runJanitor(c *cache, ex time.Duration) *janitor {
+	ctx, cancel := context.WithCancel(context.Background())
 	j := &janitor{
 		Interval: ex,
-		stop:     make(chan bool, 1),
+		cancel:   cancel,
 	}
 	c.janitor = j
-	go j.Run(c)
+	j.wg.Add(1)
+	go j.Run(ctx, c)
+	return j
 }
 
-func newCache(ex time.Duration, m map[string]Item) *cache {
+func newCache(ex time.Duration, store Store) *cache {
 	if ex <= 0 {
 		ex = -1
 	}
 	c := &cache{
 		expiration: ex,
-		items:      m,
+		store:      store,
 	}
 	return c
 }
 
-func newCacheWithJanitor(ex time.Duration, m map[string]Item) *Cache {
-	c := newCache(ex, m)
-	// This trick ensures that the janitor goroutine (which--granted it
-	// was enabled--is running DeleteExpired on c forever) does not keep
-	// the returned C object from being garbage collected. When it is
-	// garbage collected, the finalizer stops the janitor goroutine, after
-	// which c can be collected.
+func newCacheWithJanitor(ex time.Duration, store Store) *Cache {
+	c := newCache(ex, store)
 	C := &Cache{c}
 	if ex > 0 {
 		runJanitor(c, ex)
-		runtime.SetFinalizer(C, stopJanitor)
 	}
 	return C
 }
 
+// Close stops the cache's janitor goroutine, if one is running, and waits
+// for it to exit before returning. The cache remains usable for Get/Set/
+// Delete after Close; only the cache-wide expiration tick and OnExpired
+// callback stop firing. Close is a no-op on a cache with no janitor (one
+// created with NoExpiration) and is safe to call more than once.
+func (c *Cache) Close() error {
+	if c.janitor == nil {
+		return nil
+	}
+	c.janitor.cancel()
+	c.janitor.wg.Wait()
+	return nil
+}
+
 // New return a new cache with a given expiration duration. If the
 // expiration duration is less than 1 (i.e. No Expiration),
 // the items in the cache never expire (by default), and must be deleted
 // manually. The OnExpired callback method is ignored, too.
-func New(expiration time.Duration) *Cache {
-	items := make(map[string]Item)
-	return newCacheWithJanitor(expiration, items)
+//
+// By default the cache is backed by a single map guarded by a mutex. Pass
+// WithShards or WithMaxItems to select a sharded or LRU-bounded store
+// instead, or WithStore to supply a custom Store implementation outright.
+func New(expiration time.Duration, opts ...Option) *Cache {
+	cfg := &cacheConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	store := cfg.store
+	switch {
+	case store != nil:
+		// WithStore takes precedence over WithShards/WithMaxItems.
+	case cfg.maxItems > 0:
+		store = newLRUStore(cfg.maxItems)
+	case cfg.shards > 0:
+		store = newShardedStore(cfg.shards)
+	default:
+		store = newMapStore()
+	}
+
+	// The janitor goroutine holds only the inner *cache, never the returned
+	// *Cache; it runs until Close is called.
+	C := newCacheWithJanitor(expiration, store)
+	C.keyFunc = cfg.keyFunc
+	return C
 }
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetWithExpirationSentinels(t *testing.T) {
+	c := New(50 * time.Millisecond)
+	defer c.Close()
+
+	c.SetWithExpiration("never", "v1", NoExpiration)
+	c.SetWithExpiration("default", "v2", DefaultExpiration)
+	c.SetWithExpiration("short", "v3", 10*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	c.DeleteExpired()
+
+	if _, found := c.Get("never"); !found {
+		t.Error("expected item set with NoExpiration to survive DeleteExpired")
+	}
+	if _, found := c.Get("default"); !found {
+		t.Error("expected item set with DefaultExpiration to still be live (cache expiration is 50ms)")
+	}
+	if _, found := c.Get("short"); found {
+		t.Error("expected item set with a 10ms override to have expired")
+	}
+}
+
+func TestAddRejectsExistingKey(t *testing.T) {
+	c := New(NoExpiration)
+	defer c.Close()
+
+	if err := c.Add("k", "v1", DefaultExpiration); err != nil {
+		t.Fatalf("unexpected error on first Add: %v", err)
+	}
+	if err := c.Add("k", "v2", DefaultExpiration); err == nil {
+		t.Error("expected error adding an existing key")
+	}
+
+	v, _ := c.Get("k")
+	if v != "v1" {
+		t.Errorf("expected original value v1 to be kept, got %v", v)
+	}
+}
+
+func TestReplaceWithExpirationRequiresExistingKey(t *testing.T) {
+	c := New(NoExpiration)
+	defer c.Close()
+
+	if err := c.ReplaceWithExpiration("missing", "v", DefaultExpiration); err == nil {
+		t.Error("expected error replacing a missing key")
+	}
+
+	c.Set("k", "v1")
+	if err := c.ReplaceWithExpiration("k", "v2", NoExpiration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, _ := c.Get("k")
+	if v != "v2" {
+		t.Errorf("expected v2, got %v", v)
+	}
+}
+
+func TestOnEvictedFiresOnDeleteAndFlush(t *testing.T) {
+	c := New(NoExpiration)
+	defer c.Close()
+
+	var evicted []string
+	c.OnEvicted(func(k string, v interface{}) {
+		evicted = append(evicted, k)
+	})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Delete("a")
+	c.Flush()
+
+	if len(evicted) != 2 {
+		t.Fatalf("expected 2 evictions, got %d: %v", len(evicted), evicted)
+	}
+}
+
+func TestOnEvictedFiresOnLRUEviction(t *testing.T) {
+	c := New(NoExpiration, WithMaxItems(1))
+	defer c.Close()
+
+	var evictedKey string
+	c.OnEvicted(func(k string, v interface{}) {
+		evictedKey = k
+	})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	if evictedKey != "a" {
+		t.Errorf("expected a to be evicted, got %q", evictedKey)
+	}
+}
+
+func TestGetWithExpiration(t *testing.T) {
+	c := New(NoExpiration)
+	defer c.Close()
+
+	c.SetWithExpiration("never", "v", NoExpiration)
+	_, exp, found := c.GetWithExpiration("never")
+	if !found {
+		t.Fatal("expected item to be found")
+	}
+	if !exp.IsZero() {
+		t.Errorf("expected zero expiration time for a NoExpiration item, got %v", exp)
+	}
+
+	d := 50 * time.Millisecond
+	before := time.Now()
+	c.SetWithExpiration("soon", "v", d)
+	_, exp, found = c.GetWithExpiration("soon")
+	if !found {
+		t.Fatal("expected item to be found")
+	}
+	if exp.Before(before.Add(d)) {
+		t.Errorf("expected expiration at or after now+%v, got %v", d, exp)
+	}
+}
+
+func TestItemsSnapshot(t *testing.T) {
+	c := New(NoExpiration)
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	items := c.Items()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	items["c"] = Item{Object: 3}
+	if _, found := c.Get("c"); found {
+		t.Error("expected Items() to return a copy, not a live view")
+	}
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCloseStopsJanitorGoroutine(t *testing.T) {
+	c := New(5 * time.Millisecond)
+
+	ticked := make(chan struct{}, 1)
+	c.OnExpired(func(ctx context.Context) {
+		select {
+		case ticked <- struct{}{}:
+		default:
+		}
+	})
+
+	select {
+	case <-ticked:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("expected OnExpired to fire at least once before Close")
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error from Close: %v", err)
+	}
+
+	// Close() waits on the janitor's WaitGroup, so by the time it returns
+	// the ticker goroutine has already exited and can't fire again.
+	for {
+		select {
+		case <-ticked:
+		default:
+			goto drained
+		}
+	}
+drained:
+
+	select {
+	case <-ticked:
+		t.Error("expected no further OnExpired ticks after Close")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Close is idempotent.
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error from second Close: %v", err)
+	}
+}
+
+func TestCloseOnNoExpirationCacheIsNoop(t *testing.T) {
+	c := New(NoExpiration)
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
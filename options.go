@@ -0,0 +1,46 @@
+package cache
+
+// Option configures a Cache constructed by New.
+type Option func(*cacheConfig)
+
+type cacheConfig struct {
+	store    Store
+	shards   int
+	maxItems int
+	keyFunc  func(interface{}) (string, error)
+}
+
+// WithStore overrides the cache's backing Store entirely, taking precedence
+// over WithShards and WithMaxItems if more than one is given.
+func WithStore(s Store) Option {
+	return func(cfg *cacheConfig) {
+		cfg.store = s
+	}
+}
+
+// WithShards selects the sharded Store, striping keys across n independent
+// submaps to reduce mutex contention under concurrent load. Ignored if
+// WithStore or WithMaxItems is also given, or if n is not positive.
+func WithShards(n int) Option {
+	return func(cfg *cacheConfig) {
+		cfg.shards = n
+	}
+}
+
+// WithMaxItems selects the bounded LRU Store, evicting the least-recently-used
+// item once the cache holds more than n items. Ignored if WithStore is also
+// given; takes precedence over WithShards.
+func WithMaxItems(n int) Option {
+	return func(cfg *cacheConfig) {
+		cfg.maxItems = n
+	}
+}
+
+// WithKeyFunc configures a function that derives a cache key from an
+// arbitrary object, enabling GetObj/SetObj/DeleteObj to accept typed objects
+// instead of pre-formatted string keys.
+func WithKeyFunc(f func(interface{}) (string, error)) Option {
+	return func(cfg *cacheConfig) {
+		cfg.keyFunc = f
+	}
+}
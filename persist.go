@@ -0,0 +1,68 @@
+package cache
+
+// persist.go reintroduces the persistence that upstream patrickmn/go-cache
+// offers via SaveFile/LoadFile, but as a stream-based API: callers wire the
+// io.Writer/io.Reader to whatever backend they like (disk, S3, Redis, ...)
+// themselves.
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Register registers value's concrete type with encoding/gob so it can be
+// encoded by Save and decoded by Load. Save registers the concrete type of
+// every item it encodes itself, but Load does not: every concrete type that
+// might end up behind an Item.Object must be passed to Register before Load
+// is called, and Load will fail to decode a snapshot containing an
+// unregistered type.
+func Register(value interface{}) {
+	gob.Register(value)
+}
+
+// Save writes a gob-encoded snapshot of the cache's items, including
+// expired ones, to w.
+func (c *cache) Save(w io.Writer) (err error) {
+	enc := gob.NewEncoder(w)
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("Error registering item types with Gob library")
+		}
+	}()
+	items := c.Items()
+	for _, v := range items {
+		gob.Register(v.Object)
+	}
+	err = enc.Encode(&items)
+	return
+}
+
+// Load decodes a gob-encoded snapshot written by Save from r and adds its
+// items to the cache. An item already present in the cache is kept unless
+// it has expired, in which case the snapshot's copy replaces it.
+func (c *cache) Load(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	items := map[string]Item{}
+	err := dec.Decode(&items)
+	if err == nil {
+		for k, v := range items {
+			existing, found := c.store.Get(k)
+			if !found || existing.Expired() {
+				c.store.Set(k, v)
+			}
+		}
+	}
+	return err
+}
+
+// NewFrom returns a new cache with a given expiration duration, seeded with
+// items — typically decoded from a snapshot written by Save.
+func NewFrom(expiration time.Duration, items map[string]Item) *Cache {
+	store := newMapStore()
+	for k, v := range items {
+		store.Set(k, v)
+	}
+	return newCacheWithJanitor(expiration, store)
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+)
+
+type persistPayload struct {
+	Name string
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	Register(persistPayload{})
+
+	src := New(NoExpiration)
+	defer src.Close()
+	src.Set("str", "hello")
+	src.Set("struct", persistPayload{Name: "widget"})
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	dst := New(NoExpiration)
+	defer dst.Close()
+	if err := dst.Load(&buf); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	v, found := dst.Get("str")
+	if !found || v != "hello" {
+		t.Errorf(`expected "hello", got %v (found=%v)`, v, found)
+	}
+
+	p, found := dst.Get("struct")
+	if !found {
+		t.Fatal("expected struct item to be found")
+	}
+	if pp, ok := p.(persistPayload); !ok || pp.Name != "widget" {
+		t.Errorf("expected persistPayload{Name: \"widget\"}, got %#v", p)
+	}
+}
+
+func TestLoadKeepsExistingUnexpiredItem(t *testing.T) {
+	src := New(NoExpiration)
+	defer src.Close()
+	src.Set("k", "from-snapshot")
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	dst := New(NoExpiration)
+	defer dst.Close()
+	dst.Set("k", "already-here")
+	if err := dst.Load(&buf); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	v, _ := dst.Get("k")
+	if v != "already-here" {
+		t.Errorf("expected existing unexpired item to be kept, got %v", v)
+	}
+}
+
+func TestNewFromSeedsItems(t *testing.T) {
+	items := map[string]Item{
+		"a": {Object: 1},
+		"b": {Object: 2},
+	}
+	c := NewFrom(NoExpiration, items)
+	defer c.Close()
+
+	if v, found := c.Get("a"); !found || v != 1 {
+		t.Errorf("expected a=1, got %v (found=%v)", v, found)
+	}
+	if v, found := c.Get("b"); !found || v != 2 {
+		t.Errorf("expected b=2, got %v (found=%v)", v, found)
+	}
+}
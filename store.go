@@ -0,0 +1,335 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// Store is the backing storage for a Cache. The zero-value default is
+// mapStore, a single map guarded by a sync.RWMutex; WithShards and
+// WithMaxItems select the shardedStore and lruStore implementations below,
+// and WithStore accepts any other implementation of this interface.
+//
+// Implementations are responsible for their own concurrency control; Cache
+// does not hold a lock around calls into Store.
+type Store interface {
+	Get(k string) (Item, bool)
+	Set(k string, item Item)
+	Add(k string, item Item) error
+	Delete(k string) (Item, bool)
+	Replace(k string, item Item) error
+	Range(f func(k string, item Item) bool)
+	Len() int
+	Flush()
+}
+
+// mapStore is the default Store: a single map guarded by a sync.RWMutex.
+type mapStore struct {
+	mu    sync.RWMutex
+	items map[string]Item
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{items: make(map[string]Item)}
+}
+
+func (s *mapStore) Get(k string) (Item, bool) {
+	s.mu.RLock()
+	item, found := s.items[k]
+	s.mu.RUnlock()
+	return item, found
+}
+
+func (s *mapStore) Set(k string, item Item) {
+	s.mu.Lock()
+	s.items[k] = item
+	s.mu.Unlock()
+}
+
+func (s *mapStore) Delete(k string) (Item, bool) {
+	s.mu.Lock()
+	item, found := s.items[k]
+	delete(s.items, k)
+	s.mu.Unlock()
+	return item, found
+}
+
+func (s *mapStore) Add(k string, item Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.items[k]; found {
+		return fmt.Errorf("Item %s already exists", k)
+	}
+	s.items[k] = item
+	return nil
+}
+
+func (s *mapStore) Replace(k string, item Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.items[k]; !found {
+		return fmt.Errorf("Item %s doesn't exist", k)
+	}
+	s.items[k] = item
+	return nil
+}
+
+func (s *mapStore) Range(f func(k string, item Item) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for k, item := range s.items {
+		if !f(k, item) {
+			return
+		}
+	}
+}
+
+func (s *mapStore) Len() int {
+	s.mu.RLock()
+	n := len(s.items)
+	s.mu.RUnlock()
+	return n
+}
+
+func (s *mapStore) Flush() {
+	s.mu.Lock()
+	s.items = make(map[string]Item)
+	s.mu.Unlock()
+}
+
+// defaultShards is used by newShardedStore when it is given a non-positive
+// shard count. New builds a sharded store only for a positive WithShards
+// count, so through New this default is not reached.
+const defaultShards = 32
+
+// FNV-1a 32-bit constants, inlined below to hash shard keys without the
+// heap allocation a hash.Hash32 from hash/fnv would cost on every op.
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+)
+
+// shardedStore stripes keys across n independent mapStores to reduce mutex
+// contention under concurrent load.
+type shardedStore struct {
+	shards []*mapStore
+	n      uint32
+}
+
+func newShardedStore(n int) *shardedStore {
+	if n <= 0 {
+		n = defaultShards
+	}
+	shards := make([]*mapStore, n)
+	for i := range shards {
+		shards[i] = newMapStore()
+	}
+	return &shardedStore{shards: shards, n: uint32(n)}
+}
+
+func (s *shardedStore) shardFor(k string) *mapStore {
+	h := uint32(fnvOffset32)
+	for i := 0; i < len(k); i++ {
+		h ^= uint32(k[i])
+		h *= fnvPrime32
+	}
+	return s.shards[h%s.n]
+}
+
+func (s *shardedStore) Get(k string) (Item, bool) {
+	return s.shardFor(k).Get(k)
+}
+
+func (s *shardedStore) Set(k string, item Item) {
+	s.shardFor(k).Set(k, item)
+}
+
+func (s *shardedStore) Delete(k string) (Item, bool) {
+	return s.shardFor(k).Delete(k)
+}
+
+func (s *shardedStore) Add(k string, item Item) error {
+	return s.shardFor(k).Add(k, item)
+}
+
+func (s *shardedStore) Replace(k string, item Item) error {
+	return s.shardFor(k).Replace(k, item)
+}
+
+func (s *shardedStore) Range(f func(k string, item Item) bool) {
+	for _, shard := range s.shards {
+		done := false
+		shard.Range(func(k string, item Item) bool {
+			if !f(k, item) {
+				done = true
+				return false
+			}
+			return true
+		})
+		if done {
+			return
+		}
+	}
+}
+
+func (s *shardedStore) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+func (s *shardedStore) Flush() {
+	for _, shard := range s.shards {
+		shard.Flush()
+	}
+}
+
+// evictionNotifier is implemented by stores that can evict items on their
+// own — lruStore, once it exceeds maxItems — and so need a way to report
+// those evictions back to the cache's OnEvicted callback.
+type evictionNotifier interface {
+	onEvict(f func(key string, value interface{}))
+}
+
+// lruEntry is the value held by each element of lruStore's list.
+type lruEntry struct {
+	key  string
+	item Item
+}
+
+// lruStore is a bounded Store that evicts the least-recently-used entry
+// once more than maxItems are held.
+type lruStore struct {
+	mu       sync.Mutex
+	maxItems int
+	ll       *list.List
+	elems    map[string]*list.Element
+	evicted  func(key string, value interface{})
+}
+
+func newLRUStore(maxItems int) *lruStore {
+	return &lruStore{
+		maxItems: maxItems,
+		ll:       list.New(),
+		elems:    make(map[string]*list.Element),
+	}
+}
+
+func (s *lruStore) Get(k string) (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, found := s.elems[k]
+	if !found {
+		return Item{}, false
+	}
+	s.ll.MoveToFront(el)
+	return el.Value.(*lruEntry).item, true
+}
+
+func (s *lruStore) Set(k string, item Item) {
+	s.mu.Lock()
+	evictedKey, evictedItem, evicted := s.set(k, item)
+	cb := s.evicted
+	s.mu.Unlock()
+	if evicted && cb != nil {
+		cb(evictedKey, evictedItem.Object)
+	}
+}
+
+// set inserts or updates k and reports, via its return values, the entry
+// evicted to keep the store within maxItems (if any). The caller is
+// responsible for invoking the eviction callback after releasing s.mu.
+func (s *lruStore) set(k string, item Item) (evictedKey string, evictedItem Item, evicted bool) {
+	if el, found := s.elems[k]; found {
+		el.Value.(*lruEntry).item = item
+		s.ll.MoveToFront(el)
+		return "", Item{}, false
+	}
+	el := s.ll.PushFront(&lruEntry{key: k, item: item})
+	s.elems[k] = el
+	if s.maxItems > 0 && s.ll.Len() > s.maxItems {
+		back := s.ll.Back()
+		if back != nil {
+			entry := back.Value.(*lruEntry)
+			s.removeElement(back)
+			return entry.key, entry.item, true
+		}
+	}
+	return "", Item{}, false
+}
+
+func (s *lruStore) Add(k string, item Item) error {
+	s.mu.Lock()
+	if _, found := s.elems[k]; found {
+		s.mu.Unlock()
+		return fmt.Errorf("Item %s already exists", k)
+	}
+	evictedKey, evictedItem, evicted := s.set(k, item)
+	cb := s.evicted
+	s.mu.Unlock()
+	if evicted && cb != nil {
+		cb(evictedKey, evictedItem.Object)
+	}
+	return nil
+}
+
+func (s *lruStore) onEvict(f func(key string, value interface{})) {
+	s.mu.Lock()
+	s.evicted = f
+	s.mu.Unlock()
+}
+
+func (s *lruStore) removeElement(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.elems, el.Value.(*lruEntry).key)
+}
+
+func (s *lruStore) Delete(k string) (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, found := s.elems[k]
+	if !found {
+		return Item{}, false
+	}
+	item := el.Value.(*lruEntry).item
+	s.removeElement(el)
+	return item, true
+}
+
+func (s *lruStore) Replace(k string, item Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.elems[k]; !found {
+		return fmt.Errorf("Item %s doesn't exist", k)
+	}
+	s.set(k, item)
+	return nil
+}
+
+func (s *lruStore) Range(f func(k string, item Item) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for el := s.ll.Front(); el != nil; el = el.Next() {
+		entry := el.Value.(*lruEntry)
+		if !f(entry.key, entry.item) {
+			return
+		}
+	}
+}
+
+func (s *lruStore) Len() int {
+	s.mu.Lock()
+	n := s.ll.Len()
+	s.mu.Unlock()
+	return n
+}
+
+func (s *lruStore) Flush() {
+	s.mu.Lock()
+	s.ll.Init()
+	s.elems = make(map[string]*list.Element)
+	s.mu.Unlock()
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLRUStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	s := newLRUStore(2)
+	s.Set("a", Item{Object: 1})
+	s.Set("b", Item{Object: 2})
+	// Touch "a" so "b" becomes the least recently used.
+	if _, found := s.Get("a"); !found {
+		t.Fatal("expected a to be found")
+	}
+	s.Set("c", Item{Object: 3})
+
+	if _, found := s.Get("b"); found {
+		t.Error("expected b to have been evicted")
+	}
+	if _, found := s.Get("a"); !found {
+		t.Error("expected a to still be present")
+	}
+	if _, found := s.Get("c"); !found {
+		t.Error("expected c to be present")
+	}
+	if n := s.Len(); n != 2 {
+		t.Errorf("expected 2 items, got %d", n)
+	}
+}
+
+func TestLRUStoreOnEvict(t *testing.T) {
+	s := newLRUStore(1)
+	var evictedKey string
+	var evictedVal interface{}
+	s.onEvict(func(k string, v interface{}) {
+		evictedKey = k
+		evictedVal = v
+	})
+
+	s.Set("a", Item{Object: "first"})
+	s.Set("b", Item{Object: "second"})
+
+	if evictedKey != "a" || evictedVal != "first" {
+		t.Errorf("expected eviction of a/first, got %s/%v", evictedKey, evictedVal)
+	}
+}
+
+func TestLRUStoreAddRejectsExistingKey(t *testing.T) {
+	s := newLRUStore(2)
+	if err := s.Add("k", Item{Object: 1}); err != nil {
+		t.Fatalf("unexpected error on first Add: %v", err)
+	}
+	if err := s.Add("k", Item{Object: 2}); err == nil {
+		t.Error("expected error adding an existing key")
+	}
+}
+
+func TestShardedStoreRangeEarlyExit(t *testing.T) {
+	s := newShardedStore(4)
+	for i := 0; i < 10; i++ {
+		s.Set(fmt.Sprintf("k%d", i), Item{Object: i})
+	}
+
+	seen := 0
+	s.Range(func(k string, item Item) bool {
+		seen++
+		return seen < 3
+	})
+
+	if seen != 3 {
+		t.Errorf("expected Range to stop after 3 items, saw %d", seen)
+	}
+}
+
+func TestShardedStoreAddRejectsExistingKey(t *testing.T) {
+	s := newShardedStore(4)
+	if err := s.Add("k", Item{Object: 1}); err != nil {
+		t.Fatalf("unexpected error on first Add: %v", err)
+	}
+	if err := s.Add("k", Item{Object: 2}); err == nil {
+		t.Error("expected error adding an existing key")
+	}
+}
+
+func TestShardedStoreLenAndFlush(t *testing.T) {
+	s := newShardedStore(4)
+	for i := 0; i < 10; i++ {
+		s.Set(fmt.Sprintf("k%d", i), Item{Object: i})
+	}
+	if n := s.Len(); n != 10 {
+		t.Errorf("expected 10 items, got %d", n)
+	}
+	s.Flush()
+	if n := s.Len(); n != 0 {
+		t.Errorf("expected 0 items after Flush, got %d", n)
+	}
+}
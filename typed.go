@@ -0,0 +1,230 @@
+package cache
+
+// typed.go provides a generics-based counterpart to Cache. It keeps its
+// items in a single map guarded by a sync.RWMutex, like the default mapStore
+// in store.go, but stores V directly instead of interface{}, so callers
+// avoid the boxing and type assertion cost on every Get/Set.
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TypedItem represents an item in a TypedCache.
+type TypedItem[V any] struct {
+	Object     V
+	Expiration int64
+}
+
+// Expired returns true if the item has expired.
+func (item TypedItem[V]) Expired() bool {
+	if item.Expiration == 0 {
+		return false
+	}
+	return time.Now().UnixNano() > item.Expiration
+}
+
+// TypedCache is the generic version of Cache.
+type TypedCache[V any] struct {
+	*typedCache[V]
+	// If this is confusing, see the comment at the bottom of NewTyped()
+}
+
+type typedCache[V any] struct {
+	expiration time.Duration
+	items      map[string]TypedItem[V]
+	mu         sync.RWMutex
+	onExpired  func()
+	janitor    *typedJanitor[V]
+}
+
+// Set add an item to the cache, replacing any existing item.
+func (c *typedCache[V]) Set(k string, x V) {
+	// "Inlining" of set
+	e := c.expirationFor()
+
+	c.mu.Lock()
+	c.items[k] = TypedItem[V]{
+		Object:     x,
+		Expiration: e,
+	}
+	c.mu.Unlock()
+}
+
+func (c *typedCache[V]) set(k string, x V) {
+	c.items[k] = TypedItem[V]{
+		Object:     x,
+		Expiration: c.expirationFor(),
+	}
+}
+
+// expirationFor resolves the cache-wide expiration into an absolute
+// expiration timestamp suitable for TypedItem.Expiration, returning 0
+// (never expire) when the cache was created with NoExpiration.
+func (c *typedCache[V]) expirationFor() int64 {
+	if c.expiration <= 0 {
+		return 0
+	}
+	return time.Now().Add(c.expiration).UnixNano()
+}
+
+// Replace set a new value for the cache key only if it already exists. Returns an error otherwise.
+func (c *typedCache[V]) Replace(k string, x V) error {
+	c.mu.Lock()
+	_, found := c.get(k)
+	if !found {
+		c.mu.Unlock()
+		return fmt.Errorf("Item %s doesn't exist", k)
+	}
+	c.set(k, x)
+	c.mu.Unlock()
+	return nil
+}
+
+// Get an item from the cache. Returns the item, or the zero value of V, and
+// a bool indicating whether the key was found.
+func (c *typedCache[V]) Get(k string) (V, bool) {
+	c.mu.RLock()
+	item, found := c.items[k]
+	if !found {
+		c.mu.RUnlock()
+		var zero V
+		return zero, false
+	}
+	c.mu.RUnlock()
+	return item.Object, true
+}
+
+func (c *typedCache[V]) get(k string) (V, bool) {
+	item, found := c.items[k]
+	if !found {
+		var zero V
+		return zero, false
+	}
+	return item.Object, true
+}
+
+// Delete an item from the cache. Does nothing if the key is not in the cache.
+func (c *typedCache[V]) Delete(k string) {
+	c.mu.Lock()
+	delete(c.items, k)
+	c.mu.Unlock()
+}
+
+// Delete all expired items from the cache.
+func (c *typedCache[V]) DeleteExpired() {
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	for k, v := range c.items {
+		if v.Expiration > 0 && now > v.Expiration {
+			delete(c.items, k)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// OnExpired sets an (optional) function that is called when the cache expires
+func (c *typedCache[V]) OnExpired(f func()) {
+	c.onExpired = f
+}
+
+// Returns the number of items in the cache, including expired items.
+func (c *typedCache[V]) ItemCount() int {
+	c.mu.RLock()
+	n := len(c.items)
+	c.mu.RUnlock()
+	return n
+}
+
+// Delete all items from the cache.
+func (c *typedCache[V]) Flush() {
+	c.mu.Lock()
+	c.items = map[string]TypedItem[V]{}
+	c.mu.Unlock()
+}
+
+type typedJanitor[V any] struct {
+	Interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// handleExpired is fired by the ticker and executes the onExpired function.
+func (c *typedCache[V]) handleExpired() {
+	if c.onExpired != nil {
+		c.onExpired()
+	}
+}
+
+// Run ticks every j.Interval until ctx is cancelled.
+func (j *typedJanitor[V]) Run(ctx context.Context, c *typedCache[V]) {
+	defer j.wg.Done()
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.handleExpired()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func runTypedJanitor[V any](c *typedCache[V], ex time.Duration) *typedJanitor[V] {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &typedJanitor[V]{
+		Interval: ex,
+		cancel:   cancel,
+	}
+	c.janitor = j
+	j.wg.Add(1)
+	go j.Run(ctx, c)
+	return j
+}
+
+func newTypedCache[V any](ex time.Duration, m map[string]TypedItem[V]) *typedCache[V] {
+	if ex <= 0 {
+		ex = -1
+	}
+	c := &typedCache[V]{
+		expiration: ex,
+		items:      m,
+	}
+	return c
+}
+
+func newTypedCacheWithJanitor[V any](ex time.Duration, m map[string]TypedItem[V]) *TypedCache[V] {
+	c := newTypedCache(ex, m)
+	C := &TypedCache[V]{c}
+	if ex > 0 {
+		runTypedJanitor(c, ex)
+	}
+	return C
+}
+
+// Close stops the cache's janitor goroutine, if one is running, and waits
+// for it to exit before returning, mirroring Cache.Close. Close is a no-op
+// on a cache with no janitor (one created with NoExpiration) and is safe to
+// call more than once.
+func (c *TypedCache[V]) Close() error {
+	if c.janitor == nil {
+		return nil
+	}
+	c.janitor.cancel()
+	c.janitor.wg.Wait()
+	return nil
+}
+
+// NewTyped return a new generic cache with a given expiration duration. If the
+// expiration duration is less than 1 (i.e. No Expiration),
+// the items in the cache never expire (by default), and must be deleted
+// manually. The OnExpired callback method is ignored, too.
+func NewTyped[V any](expiration time.Duration) *TypedCache[V] {
+	items := make(map[string]TypedItem[V])
+	// The janitor goroutine holds only the inner *typedCache[V], never the
+	// returned *TypedCache[V]; it runs until Close is called.
+	return newTypedCacheWithJanitor(expiration, items)
+}
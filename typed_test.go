@@ -0,0 +1,16 @@
+package cache
+
+import "testing"
+
+func TestTypedCacheNoExpirationSurvivesDeleteExpired(t *testing.T) {
+	c := NewTyped[string](NoExpiration)
+	defer c.Close()
+
+	c.Set("k", "v")
+	c.DeleteExpired()
+
+	v, found := c.Get("k")
+	if !found || v != "v" {
+		t.Errorf(`expected "v" to survive DeleteExpired, got %q (found=%v)`, v, found)
+	}
+}